@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// EncodeOptions carries the quality/speed knobs that used to be hard-coded
+// into the avifenc invocation. Not every backend honors every field (e.g.
+// the ffmpeg and libavif backends ignore Metadata and copy metadata with
+// exiftool instead); backends ignore the ones they don't support.
+type EncodeOptions struct {
+	MinQuantizer int      // avifenc --min (0 = lossless-ish, 63 = worst)
+	MaxQuantizer int      // avifenc --max
+	Depth        int      // output bit depth: 8, 10, or 12
+	Speed        int      // encoder speed/effort, 0 (slowest/best) - 10 (fastest); -1 = backend default
+	Metadata     Metadata `json:"-"` // EXIF/XMP/ICC blobs to preserve from the source, if any; excluded from JSON (see report.go for sidecar/report encoding)
+}
+
+// Encoder converts a single source image to an AVIF file at dstPath.
+// Implementations must not leave partial output at dstPath on error.
+type Encoder interface {
+	// Name identifies the backend for -encoder and log/error messages.
+	Name() string
+	// Available reports whether this backend can run on the current
+	// system (binary in PATH, cgo support compiled in, etc.), returning
+	// a descriptive error if not.
+	Available() error
+	// Encode converts srcPath to dstPath, honoring ctx cancellation.
+	Encode(ctx context.Context, srcPath, dstPath string, opts EncodeOptions) error
+}
+
+// encoderNames lists the backends newEncoder accepts, in the order they
+// should be tried by -encoder=auto.
+var encoderNames = []string{"avifenc", "libavif", "ffmpeg"}
+
+// newEncoder looks up a backend by name (one of encoderNames, or "auto" to
+// pick the first available one).
+func newEncoder(name string) (Encoder, error) {
+	if name == "auto" {
+		var lastErr error
+		for _, n := range encoderNames {
+			enc, err := newEncoder(n)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if err := enc.Available(); err != nil {
+				lastErr = err
+				continue
+			}
+			return enc, nil
+		}
+		return nil, fmt.Errorf("no encoder backend available, tried %v: %w", encoderNames, lastErr)
+	}
+
+	switch name {
+	case "avifenc":
+		return &avifencEncoder{}, nil
+	case "libavif":
+		return newLibavifEncoder(), nil
+	case "ffmpeg":
+		return &ffmpegEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown -encoder %q (want one of %v or \"auto\")", name, encoderNames)
+	}
+}
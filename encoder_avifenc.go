@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// avifencEncoder shells out to the avifenc binary, the tool's original
+// (and still default) encoding path.
+type avifencEncoder struct{}
+
+func (e *avifencEncoder) Name() string { return "avifenc" }
+
+func (e *avifencEncoder) Available() error {
+	if _, err := exec.LookPath("avifenc"); err != nil {
+		return fmt.Errorf("avifenc: %w", err)
+	}
+	return nil
+}
+
+func (e *avifencEncoder) Encode(ctx context.Context, srcPath, dstPath string, opts EncodeOptions) error {
+	// avifenc itself only decodes JPEG and PNG input; the scanner also
+	// accepts bmp/tiff/webp/heic for the ffmpeg backend, so reject those
+	// here rather than letting avifenc fail on them with a cryptic error.
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(srcPath), "."))
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	if ext != "jpg" && ext != "png" {
+		return fmt.Errorf("avifenc backend only supports jpg/png sources, got %q (use -encoder=ffmpeg instead)", ext)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(dstPath), "avif_tmp_*.avif")
+	if err != nil {
+		return fmt.Errorf("create temp file: %v", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	args := []string{
+		"--min", strconv.Itoa(opts.MinQuantizer),
+		"--max", strconv.Itoa(opts.MaxQuantizer),
+		"--depth", strconv.Itoa(opts.Depth),
+	}
+	if opts.Speed >= 0 {
+		args = append(args, "--speed", strconv.Itoa(opts.Speed))
+	}
+
+	// avifenc embeds metadata straight from a file containing the raw
+	// blob, so write each one out to a temp file for the duration of the
+	// call.
+	var cleanups []func()
+	defer func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}()
+	addBlobFlag := func(flag string, data []byte) error {
+		if len(data) == 0 {
+			return nil
+		}
+		path, cleanup, err := writeTempBlob(filepath.Dir(dstPath), "avif_meta_*.bin", data)
+		if err != nil {
+			return fmt.Errorf("write %s blob: %w", flag, err)
+		}
+		cleanups = append(cleanups, cleanup)
+		args = append(args, flag, path)
+		return nil
+	}
+	if err := addBlobFlag("--exif", opts.Metadata.Exif); err != nil {
+		return err
+	}
+	if err := addBlobFlag("--xmp", opts.Metadata.Xmp); err != nil {
+		return err
+	}
+	if err := addBlobFlag("--icc", opts.Metadata.ICC); err != nil {
+		return err
+	}
+
+	args = append(args, srcPath, tmpPath)
+
+	cmd := exec.CommandContext(ctx, "avifenc", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("avifenc failed: %v; output: %s", err, string(out))
+	}
+
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		if cerr := copyFile(tmpPath, dstPath); cerr != nil {
+			os.Remove(dstPath)
+			return fmt.Errorf("save output failed: rename: %v, copy: %v", err, cerr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// ffmpegEncoder shells out to ffmpeg's libaom-av1 encoder, for systems
+// that have ffmpeg (built with libaom) but not avifenc. SVT-AV1 is not
+// used: the codec is always libaom-av1.
+type ffmpegEncoder struct{}
+
+func (e *ffmpegEncoder) Name() string { return "ffmpeg" }
+
+func (e *ffmpegEncoder) Available() error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	return nil
+}
+
+func (e *ffmpegEncoder) Encode(ctx context.Context, srcPath, dstPath string, opts EncodeOptions) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(dstPath), "avif_tmp_*.avif")
+	if err != nil {
+		return fmt.Errorf("create temp file: %v", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	// ffmpeg has no separate min/max quantizer knobs for a single still
+	// image encode; average the two into a single -crf value and map
+	// Speed onto -cpu-used (libaom's speed/quality tradeoff knob).
+	crf := (opts.MinQuantizer + opts.MaxQuantizer) / 2
+	cpuUsed := opts.Speed
+	if cpuUsed < 0 {
+		cpuUsed = 4
+	}
+
+	args := []string{
+		"-y", "-loglevel", "error",
+		"-i", srcPath,
+		"-c:v", "libaom-av1",
+		"-crf", strconv.Itoa(crf),
+		"-cpu-used", strconv.Itoa(cpuUsed),
+		"-pix_fmt", depthToPixFmt(opts.Depth),
+		tmpPath,
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %v; output: %s", err, string(out))
+	}
+
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		if cerr := copyFile(tmpPath, dstPath); cerr != nil {
+			os.Remove(dstPath)
+			return fmt.Errorf("save output failed: rename: %v, copy: %v", err, cerr)
+		}
+	}
+
+	// ffmpeg doesn't preserve source metadata for this encode path, so
+	// fall back to exiftool on a best-effort basis. This runs from srcPath
+	// regardless of what extractJPEGMetadata found, since that only reads
+	// JPEG and would otherwise silently drop metadata from every other
+	// source format (HEIC, PNG, TIFF, ...).
+	_ = copyMetadataViaExiftool(srcPath, dstPath)
+	return nil
+}
+
+func depthToPixFmt(depth int) string {
+	switch depth {
+	case 12:
+		return "yuv420p12le"
+	case 8:
+		return "yuv420p"
+	default:
+		return "yuv420p10le"
+	}
+}
@@ -0,0 +1,136 @@
+//go:build cgo_libavif
+
+package main
+
+/*
+#cgo pkg-config: libavif
+#include <avif/avif.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"unsafe"
+
+	_ "image/jpeg"
+)
+
+// libavifEncoder encodes in-process via cgo against libavif, avoiding the
+// avifenc/ffmpeg subprocess round-trip. Requires building with
+// `-tags cgo_libavif` and libavif's headers/library (and pkg-config file)
+// available, which is why the default build uses the stub in
+// encoder_libavif_stub.go instead.
+type libavifEncoder struct{}
+
+func newLibavifEncoder() Encoder { return &libavifEncoder{} }
+
+func (e *libavifEncoder) Name() string { return "libavif" }
+
+func (e *libavifEncoder) Available() error {
+	return nil
+}
+
+func (e *libavifEncoder) Encode(ctx context.Context, srcPath, dstPath string, opts EncodeOptions) error {
+	img, err := decodeImage(srcPath)
+	if err != nil {
+		return fmt.Errorf("decode source: %w", err)
+	}
+
+	depth := opts.Depth
+	if depth != 8 && depth != 10 && depth != 12 {
+		depth = 10
+	}
+
+	bounds := img.Bounds()
+	avifImg := C.avifImageCreate(C.uint32_t(bounds.Dx()), C.uint32_t(bounds.Dy()), C.uint32_t(depth), C.AVIF_PIXEL_FORMAT_YUV420)
+	if avifImg == nil {
+		return fmt.Errorf("avifImageCreate failed")
+	}
+	defer C.avifImageDestroy(avifImg)
+
+	rgb := C.avifRGBImage{}
+	C.avifRGBImageSetDefaults(&rgb, avifImg)
+	rgb.format = C.AVIF_RGB_FORMAT_RGBA
+	// fillRGBA packs one byte per channel regardless of the image's bit
+	// depth, so force libavif to allocate an 8-bit-per-channel buffer
+	// rather than the 16-bit samples it defaults to for depth > 8.
+	rgb.depth = 8
+	if res := C.avifRGBImageAllocatePixels(&rgb); res != C.AVIF_RESULT_OK {
+		return fmt.Errorf("avifRGBImageAllocatePixels: %s", C.GoString(C.avifResultToString(res)))
+	}
+	defer C.avifRGBImageFreePixels(&rgb)
+
+	fillRGBA(&rgb, img)
+
+	if res := C.avifImageRGBToYUV(avifImg, &rgb); res != C.AVIF_RESULT_OK {
+		return fmt.Errorf("avifImageRGBToYUV: %s", C.GoString(C.avifResultToString(res)))
+	}
+
+	enc := C.avifEncoderCreate()
+	if enc == nil {
+		return fmt.Errorf("avifEncoderCreate failed")
+	}
+	defer C.avifEncoderDestroy(enc)
+
+	enc.minQuantizer = C.int(opts.MinQuantizer)
+	enc.maxQuantizer = C.int(opts.MaxQuantizer)
+	if opts.Speed >= 0 {
+		enc.speed = C.int(opts.Speed)
+	}
+
+	var out C.avifRWData
+	defer C.avifRWDataFree(&out)
+	if res := C.avifEncoderWrite(enc, avifImg, &out); res != C.AVIF_RESULT_OK {
+		return fmt.Errorf("avifEncoderWrite: %s", C.GoString(C.avifResultToString(res)))
+	}
+
+	data := C.GoBytes(unsafe.Pointer(out.data), C.int(out.size))
+	// The libavif encode call itself cannot be interrupted, so ctx is
+	// checked once it returns, before any output is written.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dstPath, data, 0644); err != nil {
+		os.Remove(dstPath)
+		return err
+	}
+
+	// The Go-side avifRGBImage conversion above doesn't carry metadata, so
+	// fall back to exiftool on a best-effort basis, same as the ffmpeg
+	// backend. This runs from srcPath regardless of what extractJPEGMetadata
+	// found, since that only reads JPEG and would otherwise silently drop
+	// metadata from every other source format (HEIC, PNG, TIFF, ...).
+	_ = copyMetadataViaExiftool(srcPath, dstPath)
+	return nil
+}
+
+func decodeImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+func fillRGBA(rgb *C.avifRGBImage, img image.Image) {
+	bounds := img.Bounds()
+	stride := int(rgb.rowBytes)
+	base := unsafe.Pointer(rgb.pixels)
+	for y := 0; y < bounds.Dy(); y++ {
+		row := unsafe.Slice((*byte)(unsafe.Add(base, y*stride)), bounds.Dx()*4)
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			row[x*4+0] = byte(r >> 8)
+			row[x*4+1] = byte(g >> 8)
+			row[x*4+2] = byte(b >> 8)
+			row[x*4+3] = byte(a >> 8)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !cgo_libavif
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// libavifEncoder is the stub used when built without the cgo_libavif build
+// tag (the default). See encoder_libavif_cgo.go for the real
+// implementation, which links against libavif via cgo.
+type libavifEncoder struct{}
+
+func newLibavifEncoder() Encoder { return &libavifEncoder{} }
+
+func (e *libavifEncoder) Name() string { return "libavif" }
+
+func (e *libavifEncoder) Available() error {
+	return fmt.Errorf("not built with the cgo_libavif build tag (rebuild with -tags cgo_libavif and libavif installed)")
+}
+
+func (e *libavifEncoder) Encode(ctx context.Context, srcPath, dstPath string, opts EncodeOptions) error {
+	return e.Available()
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var errNoExifDate = errors.New("no DateTimeOriginal tag found")
+
+const exifDateTimeOriginalTag = 0x9003
+const exifSubIFDTag = 0x8769
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// exifDateTimeOriginal attempts to read the EXIF DateTimeOriginal tag from
+// src. JPEG and HEIC are supported; any other format, or any read/parse
+// failure along the way, returns false so callers fall back to ModTime.
+func exifDateTimeOriginal(path, ext string) (time.Time, bool) {
+	switch ext {
+	case "jpg":
+		f, err := os.Open(path)
+		if err != nil {
+			return time.Time{}, false
+		}
+		defer f.Close()
+
+		t, err := readJPEGExifDate(f)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	case "heic":
+		t, err := exifDateTimeOriginalHEIC(path)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// readJPEGExifDate scans JPEG markers for the APP1/Exif segment and walks
+// the embedded TIFF IFDs for tag 0x9003 (DateTimeOriginal), following the
+// ExifIFD pointer (tag 0x8769) from IFD0 when present.
+func readJPEGExifDate(f *os.File) (time.Time, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(f, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return time.Time{}, errors.New("not a JPEG file")
+	}
+
+	for {
+		marker, err := readMarker(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if marker == 0xD8 || marker == 0xD9 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			continue // standalone markers, no length field
+		}
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(f, lenBuf[:]); err != nil {
+			return time.Time{}, err
+		}
+		segLen := int(binary.BigEndian.Uint16(lenBuf[:])) - 2
+		if segLen < 0 {
+			return time.Time{}, errors.New("malformed JPEG segment length")
+		}
+		if marker == 0xDA { // start of scan: image data follows, exif must precede it
+			return time.Time{}, errNoExifDate
+		}
+		payload := make([]byte, segLen)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			return time.Time{}, err
+		}
+		if marker == 0xE1 && len(payload) > 6 && string(payload[:6]) == "Exif\x00\x00" {
+			return parseExifTIFF(payload[6:])
+		}
+	}
+}
+
+func readMarker(f *os.File) (byte, error) {
+	var b [1]byte
+	for {
+		if _, err := io.ReadFull(f, b[:]); err != nil {
+			return 0, err
+		}
+		if b[0] != 0xFF {
+			continue
+		}
+		if _, err := io.ReadFull(f, b[:]); err != nil {
+			return 0, err
+		}
+		if b[0] == 0xFF || b[0] == 0x00 {
+			continue // fill byte / stuffed byte
+		}
+		return b[0], nil
+	}
+}
+
+func parseExifTIFF(tiff []byte) (time.Time, error) {
+	if len(tiff) < 8 {
+		return time.Time{}, errors.New("truncated TIFF header")
+	}
+	var order binary.ByteOrder
+	switch {
+	case tiff[0] == 'I' && tiff[1] == 'I':
+		order = binary.LittleEndian
+	case tiff[0] == 'M' && tiff[1] == 'M':
+		order = binary.BigEndian
+	default:
+		return time.Time{}, errors.New("bad TIFF byte order marker")
+	}
+
+	ifd0Offset := order.Uint32(tiff[4:8])
+	if s, ok := findIFDString(tiff, ifd0Offset, order, exifDateTimeOriginalTag); ok {
+		return time.Parse(exifDateLayout, s)
+	}
+	if subOffset, ok := findIFDUint32(tiff, ifd0Offset, order, exifSubIFDTag); ok {
+		if s, ok := findIFDString(tiff, subOffset, order, exifDateTimeOriginalTag); ok {
+			return time.Parse(exifDateLayout, s)
+		}
+	}
+	return time.Time{}, errNoExifDate
+}
+
+// findIFDString looks up a single ASCII-valued tag within the IFD at offset.
+func findIFDString(tiff []byte, offset uint32, order binary.ByteOrder, tag uint16) (string, bool) {
+	entry, ok := findIFDEntry(tiff, offset, order, tag)
+	if !ok || entry.typ != 2 {
+		return "", false
+	}
+	if entry.count <= 4 {
+		return trimNulTerm(string(entry.valueBytes[:entry.count])), true
+	}
+	if int(entry.valueOffset)+int(entry.count) > len(tiff) {
+		return "", false
+	}
+	return trimNulTerm(string(tiff[entry.valueOffset : entry.valueOffset+entry.count])), true
+}
+
+// findIFDUint32 looks up a single LONG-valued tag (e.g. an IFD pointer).
+func findIFDUint32(tiff []byte, offset uint32, order binary.ByteOrder, tag uint16) (uint32, bool) {
+	entry, ok := findIFDEntry(tiff, offset, order, tag)
+	if !ok || entry.typ != 4 {
+		return 0, false
+	}
+	return entry.valueOffset, true
+}
+
+type ifdEntry struct {
+	typ         uint16
+	count       uint32
+	valueOffset uint32
+	valueBytes  [4]byte
+}
+
+func findIFDEntry(tiff []byte, offset uint32, order binary.ByteOrder, tag uint16) (ifdEntry, bool) {
+	if int(offset)+2 > len(tiff) {
+		return ifdEntry{}, false
+	}
+	n := int(order.Uint16(tiff[offset : offset+2]))
+	base := int(offset) + 2
+	for i := 0; i < n; i++ {
+		start := base + i*12
+		if start+12 > len(tiff) {
+			break
+		}
+		entryTag := order.Uint16(tiff[start : start+2])
+		if entryTag != tag {
+			continue
+		}
+		var e ifdEntry
+		e.typ = order.Uint16(tiff[start+2 : start+4])
+		e.count = order.Uint32(tiff[start+4 : start+8])
+		copy(e.valueBytes[:], tiff[start+8:start+12])
+		e.valueOffset = order.Uint32(tiff[start+8 : start+12])
+		return e, true
+	}
+	return ifdEntry{}, false
+}
+
+func trimNulTerm(s string) string {
+	for i, r := range s {
+		if r == 0 {
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// buildTIFF assembles a minimal valid Exif TIFF blob (in order byte order)
+// with a DateTimeOriginal tag, either directly in IFD0 or behind the
+// ExifSubIFD pointer (tag 0x8769), mirroring real camera/HEIC output where
+// DateTimeOriginal usually lives in the sub-IFD.
+func buildTIFF(order binary.ByteOrder, viaSubIFD bool, date string) []byte {
+	value := append([]byte(date), 0) // NUL-terminated ASCII, per the TIFF spec
+
+	header := func(ifd0Offset uint32) []byte {
+		b := make([]byte, 8)
+		if order == binary.LittleEndian {
+			b[0], b[1] = 'I', 'I'
+		} else {
+			b[0], b[1] = 'M', 'M'
+		}
+		order.PutUint16(b[2:4], 42)
+		order.PutUint32(b[4:8], ifd0Offset)
+		return b
+	}
+
+	ifdEntryBytes := func(tag, typ uint16, count, valueOrOffset uint32) []byte {
+		b := make([]byte, 12)
+		order.PutUint16(b[0:2], tag)
+		order.PutUint16(b[2:4], typ)
+		order.PutUint32(b[4:8], count)
+		order.PutUint32(b[8:12], valueOrOffset)
+		return b
+	}
+
+	if !viaSubIFD {
+		// header(8) + IFD0[count(2) + 1 entry(12) + nextIFD(4)] = 26, then
+		// the DateTimeOriginal string follows immediately.
+		dataOffset := uint32(26)
+		var buf bytes.Buffer
+		buf.Write(header(8))
+		countBuf := make([]byte, 2)
+		order.PutUint16(countBuf, 1)
+		buf.Write(countBuf)
+		buf.Write(ifdEntryBytes(exifDateTimeOriginalTag, 2, uint32(len(value)), dataOffset))
+		buf.Write(make([]byte, 4)) // next IFD offset = 0
+		buf.Write(value)
+		return buf.Bytes()
+	}
+
+	// IFD0 at 8 points (via tag 0x8769) at a sub-IFD at offset 26, whose
+	// own data area (at 26+18=44) holds the DateTimeOriginal string.
+	subIFDOffset := uint32(26)
+	dataOffset := uint32(44)
+	var buf bytes.Buffer
+	buf.Write(header(8))
+	countBuf := make([]byte, 2)
+	order.PutUint16(countBuf, 1)
+	buf.Write(countBuf)
+	buf.Write(ifdEntryBytes(exifSubIFDTag, 4, 1, subIFDOffset))
+	buf.Write(make([]byte, 4)) // next IFD offset = 0
+	buf.Write(countBuf)        // sub-IFD entry count = 1
+	buf.Write(ifdEntryBytes(exifDateTimeOriginalTag, 2, uint32(len(value)), dataOffset))
+	buf.Write(make([]byte, 4)) // sub-IFD next offset = 0
+	buf.Write(value)
+	return buf.Bytes()
+}
+
+func TestParseExifTIFF(t *testing.T) {
+	want := time.Date(2023, 5, 4, 10, 20, 30, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		tiff    []byte
+		wantErr bool
+	}{
+		{"little-endian direct IFD0", buildTIFF(binary.LittleEndian, false, "2023:05:04 10:20:30"), false},
+		{"big-endian direct IFD0", buildTIFF(binary.BigEndian, false, "2023:05:04 10:20:30"), false},
+		{"little-endian via ExifSubIFD", buildTIFF(binary.LittleEndian, true, "2023:05:04 10:20:30"), false},
+		{"big-endian via ExifSubIFD", buildTIFF(binary.BigEndian, true, "2023:05:04 10:20:30"), false},
+		{"too short", []byte{0x49, 0x49}, true},
+		{"bad byte order marker", []byte("XX\x2a\x00\x08\x00\x00\x00"), true},
+		{"no DateTimeOriginal tag", buildTIFFNoDate(), true},
+		{"unparsable date value", buildTIFF(binary.LittleEndian, false, "not-a-date"), true},
+		{"IFD0 offset out of bounds", buildTIFFBadIFDOffset(), true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseExifTIFF(tc.tiff)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got time %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// buildTIFFNoDate is a minimal valid TIFF with an IFD0 that has zero entries.
+func buildTIFFNoDate() []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{'I', 'I'})
+	countBuf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(countBuf, 42)
+	buf.Write(countBuf)
+	off := make([]byte, 4)
+	binary.LittleEndian.PutUint32(off, 8)
+	buf.Write(off)
+	binary.LittleEndian.PutUint16(countBuf, 0) // zero IFD0 entries
+	buf.Write(countBuf)
+	buf.Write(make([]byte, 4)) // next IFD offset
+	return buf.Bytes()
+}
+
+// buildTIFFBadIFDOffset is a valid-looking TIFF header whose IFD0 offset
+// points past the end of the buffer.
+func buildTIFFBadIFDOffset() []byte {
+	b := make([]byte, 8)
+	b[0], b[1] = 'I', 'I'
+	binary.LittleEndian.PutUint16(b[2:4], 42)
+	binary.LittleEndian.PutUint32(b[4:8], 1000)
+	return b
+}
+
+// buildJPEGWithExif wraps a TIFF blob in a minimal JPEG byte stream: SOI,
+// an APP1/Exif segment carrying tiff, then SOS with no scan data.
+func buildJPEGWithExif(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8}) // SOI
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	buf.Write([]byte{0xFF, 0xE1})
+	lenBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBuf, uint16(len(payload)+2))
+	buf.Write(lenBuf)
+	buf.Write(payload)
+
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02}) // SOS, zero-length payload
+	return buf.Bytes()
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	return path
+}
+
+func TestExifDateTimeOriginalJPEG(t *testing.T) {
+	want := time.Date(2023, 5, 4, 10, 20, 30, 0, time.UTC)
+	tiff := buildTIFF(binary.LittleEndian, false, "2023:05:04 10:20:30")
+
+	cases := []struct {
+		name    string
+		data    []byte
+		wantOK  bool
+		wantVal time.Time
+	}{
+		{"valid Exif APP1", buildJPEGWithExif(tiff), true, want},
+		{"no SOI marker", []byte{0x00, 0x00, 0x01, 0x02}, false, time.Time{}},
+		{"SOS with no preceding Exif", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}, false, time.Time{}},
+		{"truncated after SOI", []byte{0xFF, 0xD8, 0xFF}, false, time.Time{}},
+		{"APP1 without Exif signature", func() []byte {
+			var buf bytes.Buffer
+			buf.Write([]byte{0xFF, 0xD8})
+			payload := []byte("notexif")
+			buf.Write([]byte{0xFF, 0xE1, 0x00, byte(len(payload) + 2)})
+			buf.Write(payload)
+			buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02})
+			return buf.Bytes()
+		}(), false, time.Time{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTempFile(t, "test.jpg", tc.data)
+			got, ok := exifDateTimeOriginal(path, "jpg")
+			if ok != tc.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tc.wantOK)
+			}
+			if ok && !got.Equal(tc.wantVal) {
+				t.Fatalf("got %v, want %v", got, tc.wantVal)
+			}
+		})
+	}
+}
+
+func TestExifDateTimeOriginalUnsupportedExt(t *testing.T) {
+	path := writeTempFile(t, "test.png", []byte("not an image"))
+	if _, ok := exifDateTimeOriginal(path, "png"); ok {
+		t.Fatalf("expected false for unsupported ext, got true")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// stringList is a repeatable flag.Value, e.g. -include "*.jpg" -include "2023/*".
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// shouldInclude applies exclude patterns first (any match rejects), then
+// include patterns (if any are given, at least one must match). Patterns
+// are matched against both the path relative to the scan root and just
+// its base name, so "-exclude *.tmp" and "-include 2023/*" both work.
+func shouldInclude(relPath string, includes, excludes []string) bool {
+	base := filepath.Base(relPath)
+	for _, pat := range excludes {
+		if globMatch(pat, relPath) || globMatch(pat, base) {
+			return false
+		}
+	}
+	if len(includes) == 0 {
+		return true
+	}
+	for _, pat := range includes {
+		if globMatch(pat, relPath) || globMatch(pat, base) {
+			return true
+		}
+	}
+	return false
+}
+
+func globMatch(pattern, name string) bool {
+	ok, err := filepath.Match(pattern, name)
+	return err == nil && ok
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"time"
+)
+
+// exifDateTimeOriginalHEIC reads the EXIF DateTimeOriginal tag from a HEIC
+// file by walking its ISOBMFF box structure to find the 'Exif' item
+// referenced from the top-level 'meta' box, then parsing the embedded TIFF
+// the same way readJPEGExifDate does for JPEG.
+//
+// Only the common case is handled: iloc construction_method 0 (file offset)
+// or 1 (offset into the 'idat' box); construction_method 2 (offset into
+// another item) is rare in practice and not supported.
+func exifDateTimeOriginalHEIC(path string) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	metaBody, err := findMetaBoxBody(f)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(metaBody) < 4 {
+		return time.Time{}, errors.New("truncated meta box")
+	}
+	children := metaBody[4:] // skip FullBox version/flags
+
+	iinf := findChildBox(children, "iinf")
+	iloc := findChildBox(children, "iloc")
+	if iinf == nil || iloc == nil {
+		return time.Time{}, errors.New("no iinf/iloc box in meta")
+	}
+
+	itemID, ok := findExifItemID(iinf)
+	if !ok {
+		return time.Time{}, errNoExifDate
+	}
+
+	extents, ok := findItemExtents(iloc, itemID)
+	if !ok || len(extents) == 0 {
+		return time.Time{}, errNoExifDate
+	}
+
+	exifItem, err := readItemExtents(f, findChildBox(children, "idat"), extents)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// The Exif item's payload starts with a 4-byte big-endian offset to the
+	// TIFF header (the bytes in between are typically an "Exif\x00\x00"
+	// prefix, mirroring the APP1 segment in JPEG).
+	if len(exifItem) < 4 {
+		return time.Time{}, errors.New("truncated Exif item")
+	}
+	tiffOffset := binary.BigEndian.Uint32(exifItem[:4])
+	if int(tiffOffset) > len(exifItem)-4 {
+		return time.Time{}, errors.New("Exif item TIFF offset out of range")
+	}
+	return parseExifTIFF(exifItem[4+tiffOffset:])
+}
+
+// findMetaBoxBody scans f's top-level ISOBMFF boxes and returns the body
+// (everything after the box header) of the first 'meta' box found.
+func findMetaBoxBody(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+
+	var pos int64
+	for pos < size {
+		var hdr [8]byte
+		if _, err := f.ReadAt(hdr[:], pos); err != nil {
+			return nil, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(hdr[0:4]))
+		typ := string(hdr[4:8])
+		headerLen := int64(8)
+		switch boxSize {
+		case 1: // 64-bit largesize follows the header
+			var ext [8]byte
+			if _, err := f.ReadAt(ext[:], pos+8); err != nil {
+				return nil, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(ext[:]))
+			headerLen = 16
+		case 0: // box extends to EOF
+			boxSize = size - pos
+		}
+		if boxSize < headerLen || pos+boxSize > size {
+			return nil, errors.New("malformed isobmff box")
+		}
+		if typ == "meta" {
+			body := make([]byte, boxSize-headerLen)
+			if _, err := f.ReadAt(body, pos+headerLen); err != nil {
+				return nil, err
+			}
+			return body, nil
+		}
+		pos += boxSize
+	}
+	return nil, errors.New("no meta box found")
+}
+
+// findChildBox returns the body of the first child box of type boxType
+// within data, a concatenation of sibling boxes (e.g. a 'meta' box's
+// children after its FullBox header).
+func findChildBox(data []byte, boxType string) []byte {
+	for len(data) >= 8 {
+		size := int64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		headerLen := int64(8)
+		if size == 1 {
+			if len(data) < 16 {
+				return nil
+			}
+			size = int64(binary.BigEndian.Uint64(data[8:16]))
+			headerLen = 16
+		} else if size == 0 {
+			size = int64(len(data))
+		}
+		if size < headerLen || size > int64(len(data)) {
+			return nil
+		}
+		if typ == boxType {
+			return data[headerLen:size]
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+// findExifItemID scans an 'iinf' box's 'infe' entries for the item whose
+// item_type is "Exif" and returns its item_ID.
+func findExifItemID(iinf []byte) (uint32, bool) {
+	if len(iinf) < 4 {
+		return 0, false
+	}
+	version := iinf[0]
+	pos := 4
+	var count int
+	if version == 0 {
+		if len(iinf) < pos+2 {
+			return 0, false
+		}
+		count = int(binary.BigEndian.Uint16(iinf[pos : pos+2]))
+		pos += 2
+	} else {
+		if len(iinf) < pos+4 {
+			return 0, false
+		}
+		count = int(binary.BigEndian.Uint32(iinf[pos : pos+4]))
+		pos += 4
+	}
+
+	data := iinf[pos:]
+	for i := 0; i < count && len(data) >= 8; i++ {
+		size := int64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		if size < 8 || size > int64(len(data)) {
+			return 0, false
+		}
+		if typ == "infe" {
+			if itemID, itemType, ok := parseInfeEntry(data[8:size]); ok && itemType == "Exif" {
+				return itemID, true
+			}
+		}
+		data = data[size:]
+	}
+	return 0, false
+}
+
+// parseInfeEntry extracts item_ID and item_type from an 'infe' FullBox
+// body. Only versions 2 and 3 are handled (libheif and iOS both write
+// v2/v3); other versions report false.
+func parseInfeEntry(b []byte) (uint32, string, bool) {
+	if len(b) < 4 {
+		return 0, "", false
+	}
+	version := b[0]
+	if version < 2 || version > 3 {
+		return 0, "", false
+	}
+	pos := 4
+
+	idSize := 2
+	if version >= 3 {
+		idSize = 4
+	}
+	if len(b) < pos+idSize+2+4 {
+		return 0, "", false
+	}
+	var itemID uint32
+	if idSize == 2 {
+		itemID = uint32(binary.BigEndian.Uint16(b[pos : pos+2]))
+	} else {
+		itemID = binary.BigEndian.Uint32(b[pos : pos+4])
+	}
+	pos += idSize
+	pos += 2 // item_protection_index
+	itemType := string(b[pos : pos+4])
+	return itemID, itemType, true
+}
+
+type ilocExtent struct {
+	constructionMethod uint8
+	baseOffset         uint64
+	extentOffset       uint64
+	extentLength       uint64
+}
+
+// findItemExtents parses an 'iloc' FullBox body and returns the extents
+// recorded for targetID.
+func findItemExtents(iloc []byte, targetID uint32) ([]ilocExtent, bool) {
+	if len(iloc) < 4 {
+		return nil, false
+	}
+	version := iloc[0]
+	pos := 4
+
+	if len(iloc) < pos+1 {
+		return nil, false
+	}
+	offsetSize := int(iloc[pos] >> 4)
+	lengthSize := int(iloc[pos] & 0xF)
+	pos++
+
+	baseOffsetSize := 0
+	indexSize := 0
+	if len(iloc) < pos+1 {
+		return nil, false
+	}
+	if version == 1 || version == 2 {
+		baseOffsetSize = int(iloc[pos] >> 4)
+		indexSize = int(iloc[pos] & 0xF)
+	} else {
+		baseOffsetSize = int(iloc[pos] >> 4)
+	}
+	pos++
+
+	var itemCount int
+	if version < 2 {
+		if len(iloc) < pos+2 {
+			return nil, false
+		}
+		itemCount = int(binary.BigEndian.Uint16(iloc[pos : pos+2]))
+		pos += 2
+	} else {
+		if len(iloc) < pos+4 {
+			return nil, false
+		}
+		itemCount = int(binary.BigEndian.Uint32(iloc[pos : pos+4]))
+		pos += 4
+	}
+
+	readUint := func(n int) (uint64, bool) {
+		if n == 0 {
+			return 0, true
+		}
+		if len(iloc) < pos+n {
+			return 0, false
+		}
+		var v uint64
+		for i := 0; i < n; i++ {
+			v = v<<8 | uint64(iloc[pos+i])
+		}
+		pos += n
+		return v, true
+	}
+
+	for i := 0; i < itemCount; i++ {
+		idSize := 2
+		if version >= 2 {
+			idSize = 4
+		}
+		itemID, ok := readUint(idSize)
+		if !ok {
+			return nil, false
+		}
+
+		var constructionMethod uint8
+		if version == 1 || version == 2 {
+			if len(iloc) < pos+2 {
+				return nil, false
+			}
+			constructionMethod = uint8(binary.BigEndian.Uint16(iloc[pos:pos+2]) & 0xF)
+			pos += 2
+		}
+
+		if len(iloc) < pos+2 {
+			return nil, false
+		}
+		pos += 2 // data_reference_index
+
+		baseOffset, ok := readUint(baseOffsetSize)
+		if !ok {
+			return nil, false
+		}
+
+		if len(iloc) < pos+2 {
+			return nil, false
+		}
+		extentCount := int(binary.BigEndian.Uint16(iloc[pos : pos+2]))
+		pos += 2
+
+		extents := make([]ilocExtent, 0, extentCount)
+		for e := 0; e < extentCount; e++ {
+			if (version == 1 || version == 2) && indexSize > 0 {
+				if _, ok := readUint(indexSize); !ok {
+					return nil, false
+				}
+			}
+			extOffset, ok := readUint(offsetSize)
+			if !ok {
+				return nil, false
+			}
+			extLength, ok := readUint(lengthSize)
+			if !ok {
+				return nil, false
+			}
+			extents = append(extents, ilocExtent{
+				constructionMethod: constructionMethod,
+				baseOffset:         baseOffset,
+				extentOffset:       extOffset,
+				extentLength:       extLength,
+			})
+		}
+
+		if uint32(itemID) == targetID {
+			return extents, true
+		}
+	}
+	return nil, false
+}
+
+// readItemExtents reads and concatenates the bytes described by extents,
+// resolving construction_method 0 against the file itself and method 1
+// against the meta box's 'idat' box. Method 2 (offset into another item)
+// is not supported.
+func readItemExtents(f *os.File, idat []byte, extents []ilocExtent) ([]byte, error) {
+	var buf []byte
+	for _, ext := range extents {
+		switch ext.constructionMethod {
+		case 0:
+			chunk := make([]byte, ext.extentLength)
+			if _, err := f.ReadAt(chunk, int64(ext.baseOffset+ext.extentOffset)); err != nil {
+				return nil, err
+			}
+			buf = append(buf, chunk...)
+		case 1:
+			if idat == nil {
+				return nil, errors.New("iloc references idat but none found")
+			}
+			start := ext.baseOffset + ext.extentOffset
+			end := start + ext.extentLength
+			if end > uint64(len(idat)) {
+				return nil, errors.New("iloc extent out of range of idat")
+			}
+			buf = append(buf, idat[start:end]...)
+		default:
+			return nil, errors.New("unsupported iloc construction_method")
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// isoBox wraps body in a standard 32-bit-size ISOBMFF box header.
+func isoBox(typ string, body []byte) []byte {
+	var buf bytes.Buffer
+	sizeBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBuf, uint32(8+len(body)))
+	buf.Write(sizeBuf)
+	buf.WriteString(typ)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// buildInfe builds a version-2 'infe' FullBox body for item itemID with the
+// given 4-character item_type, sized exactly to what parseInfeEntry reads.
+func buildInfe(itemID uint16, itemType string) []byte {
+	b := make([]byte, 12)
+	b[0] = 2 // version
+	binary.BigEndian.PutUint16(b[4:6], itemID)
+	// b[6:8] item_protection_index = 0
+	copy(b[8:12], itemType)
+	return b
+}
+
+// buildIinf builds an 'iinf' FullBox body (version 0) listing the given
+// infe entries.
+func buildIinf(infeEntries ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4)) // version/flags = 0
+	countBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(countBuf, uint16(len(infeEntries)))
+	buf.Write(countBuf)
+	for _, e := range infeEntries {
+		buf.Write(isoBox("infe", e))
+	}
+	return buf.Bytes()
+}
+
+// buildIloc builds a version-0 'iloc' FullBox body (construction_method is
+// implicitly 0, i.e. file-offset; use buildIlocV1 for other methods).
+func buildIloc(itemID uint16, baseOffset, extentOffset, extentLength uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4)) // version/flags = 0
+	buf.Write([]byte{0x44})    // offset_size=4, length_size=4
+	buf.Write([]byte{0x40})    // base_offset_size=4, index_size=0 (unused in v0)
+
+	itemCountBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(itemCountBuf, 1)
+	buf.Write(itemCountBuf)
+
+	idBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(idBuf, itemID)
+	buf.Write(idBuf)
+
+	buf.Write([]byte{0x00, 0x00}) // data_reference_index = 0
+
+	baseBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(baseBuf, baseOffset)
+	buf.Write(baseBuf)
+
+	extentCountBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(extentCountBuf, 1)
+	buf.Write(extentCountBuf)
+
+	offBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(offBuf, extentOffset)
+	buf.Write(offBuf)
+
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, extentLength)
+	buf.Write(lenBuf)
+
+	return buf.Bytes()
+}
+
+// buildIlocV1 is like buildIloc but emits version 1 (adds
+// construction_method), so construction_method 1 (idat-relative) can be
+// exercised.
+func buildIlocV1(itemID uint16, constructionMethod uint8, baseOffset, extentOffset, extentLength uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{1, 0, 0, 0}) // version 1, flags 0
+	buf.Write([]byte{0x44})       // offset_size=4, length_size=4
+	buf.Write([]byte{0x40})       // base_offset_size=4, index_size=0
+
+	itemCountBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(itemCountBuf, 1)
+	buf.Write(itemCountBuf)
+
+	idBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(idBuf, itemID)
+	buf.Write(idBuf)
+
+	buf.Write([]byte{0x00, constructionMethod})
+
+	buf.Write([]byte{0x00, 0x00}) // data_reference_index = 0
+
+	baseBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(baseBuf, baseOffset)
+	buf.Write(baseBuf)
+
+	extentCountBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(extentCountBuf, 1)
+	buf.Write(extentCountBuf)
+
+	offBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(offBuf, extentOffset)
+	buf.Write(offBuf)
+
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, extentLength)
+	buf.Write(lenBuf)
+
+	return buf.Bytes()
+}
+
+// buildMetaBody assembles a 'meta' FullBox body (version/flags + iinf +
+// iloc [+ idat]).
+func buildMetaBody(iinf, iloc, idat []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4)) // version/flags = 0
+	buf.Write(isoBox("iinf", iinf))
+	buf.Write(isoBox("iloc", iloc))
+	if idat != nil {
+		buf.Write(isoBox("idat", idat))
+	}
+	return buf.Bytes()
+}
+
+// buildHEICFile assembles a minimal ISOBMFF file: an 'ftyp' box, a 'meta'
+// box referencing a single Exif item via construction_method 0 (a file
+// offset), and the Exif item's bytes (a 4-byte TIFF-header offset followed
+// by tiff) placed right after the meta box.
+func buildHEICFile(tiff []byte) []byte {
+	ftyp := isoBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+
+	exifItem := make([]byte, 4+len(tiff)) // tiff_header_offset = 0
+	copy(exifItem[4:], tiff)
+
+	infe := buildInfe(1, "Exif")
+	iinf := buildIinf(infe)
+
+	// Build once with a placeholder extent offset to learn the meta box's
+	// length (fixed regardless of the value, since it's a 4-byte field),
+	// then rebuild with the real absolute offset of exifItem in the file.
+	placeholderIloc := buildIloc(1, 0, 0, uint32(len(exifItem)))
+	placeholderMeta := isoBox("meta", buildMetaBody(iinf, placeholderIloc, nil))
+	absOffset := uint32(len(ftyp) + len(placeholderMeta))
+
+	iloc := buildIloc(1, 0, absOffset, uint32(len(exifItem)))
+	meta := isoBox("meta", buildMetaBody(iinf, iloc, nil))
+	if len(meta) != len(placeholderMeta) {
+		panic("meta box length changed between passes")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(ftyp)
+	buf.Write(meta)
+	buf.Write(exifItem)
+	return buf.Bytes()
+}
+
+// buildHEICFileWithIdat is like buildHEICFile but stores the Exif item
+// inside the meta box's 'idat' box, referenced via construction_method 1.
+func buildHEICFileWithIdat(tiff []byte) []byte {
+	ftyp := isoBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+
+	exifItem := make([]byte, 4+len(tiff))
+	copy(exifItem[4:], tiff)
+
+	infe := buildInfe(1, "Exif")
+	iinf := buildIinf(infe)
+	iloc := buildIlocV1(1, 1, 0, 0, uint32(len(exifItem))) // offset 0 within idat
+
+	meta := isoBox("meta", buildMetaBody(iinf, iloc, exifItem))
+
+	var buf bytes.Buffer
+	buf.Write(ftyp)
+	buf.Write(meta)
+	return buf.Bytes()
+}
+
+func writeTempHEIC(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.heic")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	return path
+}
+
+func TestExifDateTimeOriginalHEIC(t *testing.T) {
+	want := time.Date(2023, 5, 4, 10, 20, 30, 0, time.UTC)
+	tiff := buildTIFF(binary.LittleEndian, true, "2023:05:04 10:20:30")
+
+	cases := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"file-offset extent (construction_method 0)", buildHEICFile(tiff), false},
+		{"idat extent (construction_method 1)", buildHEICFileWithIdat(tiff), false},
+		{"no meta box", isoBox("ftyp", []byte("heic")), true},
+		{"truncated meta box", isoBox("meta", []byte{0x00}), true},
+		{"meta without iinf/iloc", isoBox("meta", append(make([]byte, 4), isoBox("hdlr", []byte("x"))...)), true},
+		{"Exif item missing from iinf", func() []byte {
+			infe := buildInfe(1, "mime") // no Exif entry
+			iinf := buildIinf(infe)
+			iloc := buildIloc(1, 0, 0, 4)
+			meta := isoBox("meta", buildMetaBody(iinf, iloc, nil))
+			return append(isoBox("ftyp", []byte("heic")), meta...)
+		}(), true},
+		{"iloc references a different item", func() []byte {
+			infe := buildInfe(1, "Exif")
+			iinf := buildIinf(infe)
+			iloc := buildIloc(2, 0, 0, 4) // item 2, not item 1
+			meta := isoBox("meta", buildMetaBody(iinf, iloc, nil))
+			return append(isoBox("ftyp", []byte("heic")), meta...)
+		}(), true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTempHEIC(t, tc.data)
+			got, err := exifDateTimeOriginalHEIC(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got time %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestExifDateTimeOriginalDispatchesHEIC(t *testing.T) {
+	tiff := buildTIFF(binary.LittleEndian, true, "2023:05:04 10:20:30")
+	path := writeTempHEIC(t, buildHEICFile(tiff))
+	if _, ok := exifDateTimeOriginal(path, "heic"); !ok {
+		t.Fatalf("expected exifDateTimeOriginal to succeed for a well-formed HEIC fixture")
+	}
+}
+
+func TestFindChildBoxMalformed(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short header", []byte{0x00, 0x00, 0x00}},
+		{"size overflows buffer", func() []byte {
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint32(b[0:4], 100) // claims 100 bytes, buffer is only 8
+			copy(b[4:8], "iinf")
+			return b
+		}()},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := findChildBox(tc.data, "iinf"); got != nil {
+				t.Fatalf("expected nil for malformed input, got %v", got)
+			}
+		})
+	}
+}
+
+func TestReadItemExtentsUnsupportedConstructionMethod(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "dummy")
+	if err != nil {
+		t.Fatalf("create temp file: %v", err)
+	}
+	defer f.Close()
+
+	_, err = readItemExtents(f, nil, []ilocExtent{{constructionMethod: 2}})
+	if err == nil {
+		t.Fatalf("expected error for unsupported construction_method 2")
+	}
+}
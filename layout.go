@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LayoutMode selects how worker lays converted files out under outDir.
+type LayoutMode string
+
+const (
+	// LayoutFlat is the original behavior: makeOutputFilename picks a
+	// name and the file is written directly into outDir (or, with
+	// -mirror, into the matching subdirectory of outDir).
+	LayoutFlat LayoutMode = "flat"
+	// LayoutContent writes each output to a content-addressed path
+	// under outDir/content, keyed by the sha256 of the source file, and
+	// additionally links it under outDir/date/YYYY/MM by EXIF (or
+	// ModTime) capture date. Duplicate source digests are skipped.
+	LayoutContent LayoutMode = "content"
+)
+
+func parseLayoutMode(s string) (LayoutMode, error) {
+	switch LayoutMode(s) {
+	case "", LayoutFlat:
+		return LayoutFlat, nil
+	case LayoutContent:
+		return LayoutContent, nil
+	default:
+		return "", fmt.Errorf("unknown -layout %q (want %q or %q)", s, LayoutFlat, LayoutContent)
+	}
+}
+
+// digestIndex tracks source digests already converted under LayoutContent,
+// so a second copy of the same file is skipped instead of re-encoded.
+type digestIndex struct {
+	mu   sync.Mutex
+	seen map[string]string // digest -> content path
+}
+
+func newDigestIndex() *digestIndex {
+	return &digestIndex{seen: make(map[string]string)}
+}
+
+// claim records digest as belonging to contentPath if it isn't already
+// known, returning the existing content path and true if it was a dup.
+func (d *digestIndex) claim(digest, contentPath string) (existing string, isDup bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.seen[digest]; ok {
+		return existing, true
+	}
+	d.seen[digest] = contentPath
+	return "", false
+}
+
+// contentPath returns the content-addressed destination for digest:
+// <outDir>/content/<first hex byte>/<rest of digest>.avif
+func contentPath(outDir, digest string) string {
+	return filepath.Join(outDir, "content", digest[:2], digest[2:]+".avif")
+}
+
+// datePath returns the date-bucketed link path for a capture time:
+// <outDir>/date/YYYY/MM/<name>
+func datePath(outDir string, t time.Time, name string) string {
+	return filepath.Join(outDir, "date", t.Format("2006"), t.Format("01"), name)
+}
+
+// convertContentAddressed handles one job under LayoutContent: hash the
+// source, skip (DUP) if we've already converted an identical source this
+// run, otherwise encode to the content-addressed path and link it under
+// the date-bucketed path too.
+func convertContentAddressed(ctx context.Context, fi FileInfo, outDir string, digests *digestIndex, enc Encoder, opts EncodeOptions, sidecar bool) ConvertResult {
+	res := ConvertResult{Src: fi.Path, OrigBytes: fi.Size, SrcModTime: fi.ModTime}
+
+	digest := fi.Digest
+	if digest == "" {
+		d, err := sha256File(fi.Path)
+		if err != nil {
+			res.Err = fmt.Errorf("hash source: %v", err)
+			return res
+		}
+		digest = d
+	}
+	res.Digest = digest
+	dstPath := contentPath(outDir, digest)
+
+	if existing, isDup := digests.claim(digest, dstPath); isDup {
+		res.Dup = true
+		res.Dst = existing
+		return res
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0700); err != nil {
+		res.Err = fmt.Errorf("create content dir: %v", err)
+		return res
+	}
+
+	meta, _ := extractJPEGMetadata(fi.Path, fi.Ext)
+	res.MetaExif, res.MetaXmp, res.MetaICC = len(meta.Exif) > 0, len(meta.Xmp) > 0, len(meta.ICC) > 0
+	jobOpts := opts
+	jobOpts.Metadata = meta
+
+	start := time.Now()
+	err := enc.Encode(ctx, fi.Path, dstPath, jobOpts)
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Dst = dstPath
+
+	if st, err := os.Stat(dstPath); err == nil {
+		res.ConvBytes = st.Size()
+	} else {
+		res.Err = fmt.Errorf("stat output failed: %v", err)
+		return res
+	}
+
+	dateName := strings.TrimSuffix(filepath.Base(fi.Path), filepath.Ext(fi.Path)) + ".avif"
+	dateDst, err := ensureUniquePath(datePath(outDir, captureTime(fi), dateName))
+	if err != nil {
+		res.Err = fmt.Errorf("unique date path fail: %v", err)
+		return res
+	}
+	if err := linkOrCopy(dstPath, dateDst); err != nil {
+		res.Err = fmt.Errorf("link into date layout: %v", err)
+		return res
+	}
+
+	if sidecar {
+		if err := writeSidecar(dstPath, SidecarInfo{
+			SourcePath: res.Src,
+			Digest:     res.Digest,
+			Encoder:    enc.Name(),
+			Options:    jobOpts,
+			Metadata:   &meta,
+			DurationMS: res.Duration.Milliseconds(),
+			OrigBytes:  res.OrigBytes,
+			ConvBytes:  res.ConvBytes,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "WARN: sidecar for %s: %v\n", res.Src, err)
+		}
+	}
+
+	return res
+}
+
+// linkOrCopy hardlinks src to dst (creating dst's parent dir first),
+// falling back to a plain copy when the two paths are on different
+// filesystems or the filesystem doesn't support hardlinks.
+func linkOrCopy(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil || os.IsExist(err) {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// sha256File hashes the full contents of path.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// captureTime picks the EXIF DateTimeOriginal for f when available,
+// falling back to ModTime for formats we don't parse EXIF from (or when
+// the tag is missing/unparseable).
+func captureTime(f FileInfo) time.Time {
+	if t, ok := exifDateTimeOriginal(f.Path, f.Ext); ok {
+		return t
+	}
+	return f.ModTime
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"flag"
@@ -8,10 +9,11 @@ import (
 	"io"
 	"log"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -20,17 +22,29 @@ type FileInfo struct {
 	ModTime time.Time
 	Ext     string // normalized ext, e.g. "jpg", "png"
 	Size    int64
+	Digest  string // sha256 of source bytes, pre-computed during manifest diffing; may be empty
+	RelDir  string // directory relative to the scan root, "" at the root; used by -mirror
 }
 
 type ConvertResult struct {
-	Src       string
-	Dst       string
-	OrigBytes int64
-	ConvBytes int64
-	Err       error
+	Src        string
+	Dst        string
+	OrigBytes  int64
+	ConvBytes  int64
+	SrcModTime time.Time
+	Digest     string // sha256 of source bytes, used to record/verify the manifest
+	Dup        bool   // true if this source was already converted (digest match) this run
+	Duration   time.Duration
+	MetaExif   bool // whether EXIF was found in the source and preserved/attempted
+	MetaXmp    bool
+	MetaICC    bool
+	Err        error
 }
 
 func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
 	// CLI flags
 	input := flag.String("input", ".", "Directory to scan for image files")
 	format := flag.String("format", "", "Image format to convert (e.g., jpg, jpeg, heic)")
@@ -40,8 +54,33 @@ func main() {
 	listOnly := flag.Bool("list", false, "Only list available file types without converting")
 	dryRun := flag.Bool("dry-run", false, "Show what would be converted without actual conversion")
 	keepName := flag.Bool("keep-name", false, "Keep original filename (only change extension)")
+	layout := flag.String("layout", "flat", "Output layout: \"flat\" (default) or \"content\" (content-addressed + date-bucketed, with digest-based dedup)")
+	encoderName := flag.String("encoder", "avifenc", fmt.Sprintf("Encoder backend: %v, or \"auto\" to use the first available", encoderNames))
+	minQ := flag.Int("min-q", 0, "Minimum quantizer (quality ceiling; lower is better quality)")
+	maxQ := flag.Int("max-q", 20, "Maximum quantizer (quality floor; lower is better quality)")
+	depth := flag.Int("depth", 10, "Output bit depth (8, 10, or 12)")
+	speed := flag.Int("speed", -1, "Encoder speed/effort (backend-specific, 0=slowest/best .. 10=fastest); -1 uses the backend default")
+	force := flag.Bool("force", false, "Reconvert files even if the manifest cache says they're already done")
+	verify := flag.Bool("verify", false, "Re-stat manifest destinations, prune stale entries, and exit without converting")
+	recursive := flag.Bool("recursive", false, "Recurse into subdirectories of -input (opt-in; top level only by default)")
+	mirror := flag.Bool("mirror", false, "Reproduce the source subdirectory structure under -output (flat layout only)")
+	var includes, excludes stringList
+	flag.Var(&includes, "include", "Glob pattern a file must match to be converted (repeatable; matched against relative path and base name)")
+	flag.Var(&excludes, "exclude", "Glob pattern that excludes a file from conversion (repeatable; checked before -include)")
+	sidecar := flag.Bool("sidecar", false, "Write a per-file <output>.json sidecar with source path, digest, encoder options, timings, sizes, and any preserved metadata")
 	flag.Parse()
 
+	layoutMode, err := parseLayoutMode(*layout)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	enc, err := newEncoder(*encoderName)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	encOpts := EncodeOptions{MinQuantizer: *minQ, MaxQuantizer: *maxQ, Depth: *depth, Speed: *speed}
+
 	// Validate input directory
 	if *input == "" {
 		log.Fatalf("input directory is empty")
@@ -59,7 +98,7 @@ func main() {
 	}
 
 	// Scan directory
-	files, counts, err := scanDirectory(*input)
+	files, counts, err := scanDirectory(ctx, *input, *recursive, includes, excludes)
 	if err != nil {
 		log.Fatalf("scan error: %v", err)
 	}
@@ -94,6 +133,22 @@ func main() {
 		log.Fatalf("failed to create output directory: %v", err)
 	}
 
+	manifestPath := filepath.Join(outDir, manifestFileName)
+	manifest, err := loadManifest(manifestPath)
+	if err != nil {
+		log.Fatalf("manifest error: %v", err)
+	}
+
+	if *verify {
+		pruned := manifest.verify()
+		if err := manifest.save(); err != nil {
+			log.Fatalf("save manifest: %v", err)
+		}
+		fmt.Printf("Verify: pruned %d stale entr%s, %d entr%s remain\n",
+			pruned, pluralIes(pruned), len(manifest.Entries), pluralIes(len(manifest.Entries)))
+		return
+	}
+
 	// Build list to convert
 	var toConvert []FileInfo
 	for _, f := range files {
@@ -102,25 +157,58 @@ func main() {
 		}
 	}
 
+	// Diff against the manifest cache so a resumed run skips files it
+	// already converted (unless -force), without re-invoking the encoder.
+	// Cached files still get a report.json entry (from the manifest) so
+	// the report reflects the full directory, not just this run's work.
+	var skipped int
+	var reportEntries []ReportEntry
+	if !*force {
+		var pending []FileInfo
+		for _, f := range toConvert {
+			digest, herr := sha256File(f.Path)
+			if herr != nil {
+				pending = append(pending, f)
+				continue
+			}
+			if e, ok := manifest.lookup(f, digest); ok {
+				skipped++
+				reportEntries = append(reportEntries, reportEntryFromManifest(e))
+				continue
+			}
+			f.Digest = digest
+			pending = append(pending, f)
+		}
+		toConvert = pending
+	}
+	if skipped > 0 {
+		fmt.Printf("\nSkipping %d already-converted file(s) per %s (use -force to reconvert)\n", skipped, manifestFileName)
+	}
+
 	fmt.Printf("\nConverting %d .%s files to AVIF (workers=%d)\n", len(toConvert), *format, *workers)
 	if *dryRun {
 		fmt.Println("DRY RUN - no conversion will be performed")
 		for _, f := range toConvert {
 			outName := makeOutputFilename(f, *prefix, *keepName)
-			fmt.Printf("%s -> %s (%.2f MB)\n", f.Path, filepath.Join(outDir, outName), float64(f.Size)/(1024*1024))
+			outSubDir := outDir
+			if *mirror && f.RelDir != "" {
+				outSubDir = filepath.Join(outDir, f.RelDir)
+			}
+			fmt.Printf("%s -> %s (%.2f MB)\n", f.Path, filepath.Join(outSubDir, outName), float64(f.Size)/(1024*1024))
 		}
 		return
 	}
 
-	// Check avifenc in PATH
-	if _, err := exec.LookPath("avifenc"); err != nil {
-		log.Fatalf("avifenc not found in PATH: %v", err)
+	// Probe the chosen encoder backend
+	if err := enc.Available(); err != nil {
+		log.Fatalf("encoder %q unavailable: %v", enc.Name(), err)
 	}
 
 	// Start worker pool
 	results := make(chan ConvertResult, len(toConvert))
 	var wg sync.WaitGroup
 	jobCh := make(chan FileInfo, len(toConvert))
+	digests := newDigestIndex()
 
 	// Start workers
 	numWorkers := *workers
@@ -129,15 +217,20 @@ func main() {
 	}
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go worker(&wg, jobCh, results, outDir, *prefix, *keepName)
+		go worker(ctx, &wg, jobCh, results, outDir, *prefix, *keepName, *mirror, *sidecar, layoutMode, digests, enc, encOpts)
 	}
 
-	// Feed jobs
+	// Feed jobs, stopping early (without leaking this goroutine) if ctx
+	// is cancelled before everything has been handed off.
 	go func() {
+		defer close(jobCh)
 		for _, f := range toConvert {
-			jobCh <- f
+			select {
+			case jobCh <- f:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(jobCh)
 	}()
 
 	// Close results when done
@@ -147,12 +240,16 @@ func main() {
 	}()
 
 	// Collect
-	var success, fail int
+	var success, fail, dup int
 	var totalOrig, totalConv int64
 	for r := range results {
+		reportEntries = append(reportEntries, reportEntryFrom(r, enc.Name()))
 		if r.Err != nil {
 			fail++
 			fmt.Fprintf(os.Stderr, "ERROR: %s -> %v\n", r.Src, r.Err)
+		} else if r.Dup {
+			dup++
+			fmt.Printf("DUP: %s -> %s (already converted)\n", filepath.Base(r.Src), r.Dst)
 		} else {
 			success++
 			fmt.Printf("OK: %s -> %s (%.2f MB -> %.2f MB, %.1f%% reduction)\n",
@@ -164,10 +261,33 @@ func main() {
 			totalOrig += r.OrigBytes
 			totalConv += r.ConvBytes
 		}
+		if r.Err == nil && r.Digest != "" {
+			manifest.record(ManifestEntry{
+				SourcePath:  r.Src,
+				Size:        r.OrigBytes,
+				ModTime:     r.SrcModTime,
+				Digest:      r.Digest,
+				Dst:         r.Dst,
+				Encoder:     enc.Name(),
+				Options:     encOpts,
+				ConvBytes:   r.ConvBytes,
+				ConvertedAt: time.Now(),
+			})
+		}
+	}
+	if err := manifest.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save manifest: %v\n", err)
+	}
+	if err := writeReport(filepath.Join(outDir, reportFileName), reportEntries); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
 	}
 
 	// Summary
-	fmt.Printf("\nSummary: %d successful, %d failed\n", success, fail)
+	fmt.Println()
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted - showing partial summary")
+	}
+	fmt.Printf("Summary: %d successful, %d duplicate, %d failed\n", success, dup, fail)
 	if success > 0 && totalOrig > 0 {
 		fmt.Printf("Total size: %.2f MB -> %.2f MB (%.1f%% reduction)\n",
 			float64(totalOrig)/(1024*1024),
@@ -177,8 +297,11 @@ func main() {
 }
 
 // scanDirectory walks the inputDir and returns files and a map of counts by extension (normalized).
-// Note: this function treats "jpeg" as "jpg".
-func scanDirectory(inputDir string) ([]FileInfo, map[string]int, error) {
+// Note: this function treats "jpeg" as "jpg". It stops early (returning
+// ctx.Err()) if ctx is cancelled mid-walk. If recursive is false, only the
+// top level of inputDir is scanned. includes/excludes are glob patterns
+// (see shouldInclude) applied to each candidate file.
+func scanDirectory(ctx context.Context, inputDir string, recursive bool, includes, excludes []string) ([]FileInfo, map[string]int, error) {
 	var out []FileInfo
 	counts := map[string]int{}
 
@@ -189,16 +312,22 @@ func scanDirectory(inputDir string) ([]FileInfo, map[string]int, error) {
 		"bmp":  true,
 		"tiff": true,
 		"webp": true,
-		"heic": true, // will be included but we won't parse EXIF/time specially
+		"heic": true,
 	}
 
 	err := filepath.WalkDir(inputDir, func(path string, d os.DirEntry, walkErr error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if walkErr != nil {
 			// don't panic; skip and log
 			log.Printf("skip %s: %v", path, walkErr)
 			return nil
 		}
 		if d.IsDir() {
+			if !recursive && path != inputDir {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
@@ -212,16 +341,28 @@ func scanDirectory(inputDir string) ([]FileInfo, map[string]int, error) {
 		if ext == "jpeg" {
 			ext = "jpg"
 		}
+		relPath, err := filepath.Rel(inputDir, path)
+		if err != nil {
+			relPath = path
+		}
+		if !shouldInclude(relPath, includes, excludes) {
+			return nil
+		}
 		info, err := d.Info()
 		if err != nil {
 			log.Printf("can't stat %s: %v", path, err)
 			return nil
 		}
+		relDir := filepath.Dir(relPath)
+		if relDir == "." {
+			relDir = ""
+		}
 		fi := FileInfo{
 			Path:    path,
 			ModTime: info.ModTime(), // use ModTime only (cross-platform safe)
 			Ext:     ext,
 			Size:    info.Size(),
+			RelDir:  relDir,
 		}
 		out = append(out, fi)
 		counts[ext]++
@@ -260,12 +401,44 @@ func makeOutputFilename(f FileInfo, prefix string, keepName bool) string {
 	return fmt.Sprintf("%s_%s.avif", dateStr, rnd)
 }
 
-func worker(wg *sync.WaitGroup, jobs <-chan FileInfo, results chan<- ConvertResult, outDir, prefix string, keepName bool) {
+// worker pulls jobs until jobs is closed or ctx is cancelled (SIGINT/SIGTERM).
+// On cancellation it stops picking up new jobs; a job already in flight runs
+// to completion or is killed via the Encoder's ctx-aware subprocess, with
+// its tmp file cleaned up either way.
+func worker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan FileInfo, results chan<- ConvertResult, outDir, prefix string, keepName, mirror, sidecar bool, layout LayoutMode, digests *digestIndex, enc Encoder, opts EncodeOptions) {
 	defer wg.Done()
-	for fi := range jobs {
-		res := ConvertResult{Src: fi.Path, OrigBytes: fi.Size}
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		var fi FileInfo
+		var ok bool
+		select {
+		case fi, ok = <-jobs:
+			if !ok {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		if layout == LayoutContent {
+			results <- convertContentAddressed(ctx, fi, outDir, digests, enc, opts, sidecar)
+			continue
+		}
+
+		res := ConvertResult{Src: fi.Path, OrigBytes: fi.Size, SrcModTime: fi.ModTime}
 		outName := makeOutputFilename(fi, prefix, keepName)
-		outPath := filepath.Join(outDir, outName)
+		outSubDir := outDir
+		if mirror && fi.RelDir != "" {
+			outSubDir = filepath.Join(outDir, fi.RelDir)
+			if err := os.MkdirAll(outSubDir, 0700); err != nil {
+				res.Err = fmt.Errorf("create mirrored output dir: %v", err)
+				results <- res
+				continue
+			}
+		}
+		outPath := filepath.Join(outSubDir, outName)
 
 		// ensure unique path (avoid overwrite)
 		uniquePath, err := ensureUniquePath(outPath)
@@ -276,51 +449,20 @@ func worker(wg *sync.WaitGroup, jobs <-chan FileInfo, results chan<- ConvertResu
 		}
 		res.Dst = uniquePath
 
-		// create temp file in same directory as final output for atomic rename where possible
-		tmpFile, err := os.CreateTemp(filepath.Dir(uniquePath), "avif_tmp_*.avif")
-		if err != nil {
-			res.Err = fmt.Errorf("create temp file: %v", err)
-			results <- res
-			continue
-		}
-		tmpPath := tmpFile.Name()
-		tmpFile.Close()
-
-		// tmpRemoved tracks whether we already removed the tmp file
-		tmpRemoved := false
-		removeTmp := func() {
-			if !tmpRemoved {
-				_ = os.Remove(tmpPath)
-				tmpRemoved = true
-			}
-		}
+		meta, _ := extractJPEGMetadata(fi.Path, fi.Ext)
+		res.MetaExif, res.MetaXmp, res.MetaICC = len(meta.Exif) > 0, len(meta.Xmp) > 0, len(meta.ICC) > 0
+		jobOpts := opts
+		jobOpts.Metadata = meta
 
-		// run avifenc
-		cmd := exec.Command("avifenc", "--min", "0", "--max", "20", "--depth", "10", fi.Path, tmpPath)
-		out, err := cmd.CombinedOutput()
+		start := time.Now()
+		err = enc.Encode(ctx, fi.Path, uniquePath, jobOpts)
+		res.Duration = time.Since(start)
 		if err != nil {
-			removeTmp()
-			res.Err = fmt.Errorf("avifenc failed: %v; output: %s", err, string(out))
+			res.Err = err
 			results <- res
 			continue
 		}
 
-		// try rename; fallback to copy if cross-device
-		if err := os.Rename(tmpPath, uniquePath); err != nil {
-			if cerr := copyFile(tmpPath, uniquePath); cerr != nil {
-				removeTmp()
-				res.Err = fmt.Errorf("save output failed: rename: %v, copy: %v", err, cerr)
-				results <- res
-				continue
-			}
-			// copy succeeded, remove tmp
-			_ = os.Remove(tmpPath)
-			tmpRemoved = true
-		} else {
-			// rename succeeded: tmp no longer exists
-			tmpRemoved = true
-		}
-
 		// stat converted
 		if st, err := os.Stat(uniquePath); err == nil {
 			res.ConvBytes = st.Size()
@@ -329,7 +471,29 @@ func worker(wg *sync.WaitGroup, jobs <-chan FileInfo, results chan<- ConvertResu
 			results <- res
 			continue
 		}
+		res.Digest = fi.Digest
+		if res.Digest == "" {
+			if d, derr := sha256File(fi.Path); derr == nil {
+				res.Digest = d
+			}
+		}
 		res.Err = nil
+
+		if sidecar {
+			if err := writeSidecar(uniquePath, SidecarInfo{
+				SourcePath: res.Src,
+				Digest:     res.Digest,
+				Encoder:    enc.Name(),
+				Options:    jobOpts,
+				Metadata:   &meta,
+				DurationMS: res.Duration.Milliseconds(),
+				OrigBytes:  res.OrigBytes,
+				ConvBytes:  res.ConvBytes,
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "WARN: sidecar for %s: %v\n", res.Src, err)
+			}
+		}
+
 		results <- res
 	}
 }
@@ -371,6 +535,14 @@ func copyFile(src, dst string) error {
 	return err
 }
 
+// pluralIes returns "y" for n == 1 and "ies" otherwise (entry/entries).
+func pluralIes(n int) string {
+	if n == 1 {
+		return "y"
+	}
+	return "ies"
+}
+
 func reductionPercent(orig, conv int64) float64 {
 	if orig == 0 {
 		return 0.0
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// manifestFileName is the cache file written inside outDir so a later run
+// over the same directory can skip files it already converted.
+const manifestFileName = ".avif-converter-cache"
+
+// ManifestEntry records everything needed to decide whether a source file
+// can be skipped on a later run, and where its output went.
+type ManifestEntry struct {
+	SourcePath  string        `json:"source_path"`
+	Size        int64         `json:"size"`
+	ModTime     time.Time     `json:"mod_time"`
+	Digest      string        `json:"digest"` // sha256 of source bytes
+	Dst         string        `json:"dst"`
+	Encoder     string        `json:"encoder"`
+	Options     EncodeOptions `json:"options"`
+	ConvBytes   int64         `json:"conv_bytes"`
+	ConvertedAt time.Time     `json:"converted_at"`
+}
+
+// Manifest is a JSON-backed cache of ManifestEntry, keyed by source path.
+// It is safe for concurrent use from multiple workers.
+type Manifest struct {
+	mu      sync.Mutex
+	path    string
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+// loadManifest reads path if it exists, or returns an empty Manifest ready
+// to be populated (and later saved to path) if it doesn't.
+func loadManifest(path string) (*Manifest, error) {
+	m := &Manifest{path: path, Entries: make(map[string]ManifestEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	if len(data) == 0 {
+		return m, nil
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	if m.Entries == nil {
+		m.Entries = make(map[string]ManifestEntry)
+	}
+	return m, nil
+}
+
+// save writes the manifest to its path atomically (temp file + rename).
+func (m *Manifest) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), ".avif-converter-cache-tmp-*")
+	if err != nil {
+		return fmt.Errorf("create manifest temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("write manifest temp file: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename manifest into place: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the cached entry for fi if its size, mtime, and digest all
+// still match, so the caller can skip re-converting it.
+func (m *Manifest) lookup(fi FileInfo, digest string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.Entries[fi.Path]
+	if !ok {
+		return ManifestEntry{}, false
+	}
+	if e.Size != fi.Size || !e.ModTime.Equal(fi.ModTime) || e.Digest != digest {
+		return ManifestEntry{}, false
+	}
+	return e, true
+}
+
+// record stores (or overwrites) the entry for a successfully converted file.
+func (m *Manifest) record(e ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Entries[e.SourcePath] = e
+}
+
+// verify re-stats every entry's destination and drops entries whose output
+// no longer exists, returning how many were pruned.
+func (m *Manifest) verify() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pruned := 0
+	for path, e := range m.Entries {
+		if _, err := os.Stat(e.Dst); err != nil {
+			delete(m.Entries, path)
+			pruned++
+		}
+	}
+	return pruned
+}
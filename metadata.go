@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+var xmpSignature = []byte("http://ns.adobe.com/xap/1.0/\x00")
+var iccSignature = []byte("ICC_PROFILE\x00")
+
+// Metadata holds the raw metadata blobs extracted from a source image,
+// ready to be embedded into (or copied onto) the AVIF output.
+type Metadata struct {
+	Exif []byte `json:"exif,omitempty"`
+	Xmp  []byte `json:"xmp,omitempty"`
+	ICC  []byte `json:"icc,omitempty"`
+}
+
+// any reports whether any metadata was found.
+func (m Metadata) any() bool {
+	return len(m.Exif) > 0 || len(m.Xmp) > 0 || len(m.ICC) > 0
+}
+
+// extractJPEGMetadata scans a JPEG's APP segments for embedded Exif, XMP,
+// and ICC profile data. Only JPEG is supported; other formats return a
+// zero Metadata and no error, since callers treat missing metadata as
+// "nothing to preserve" rather than a failure.
+//
+// ICC profiles that are split across multiple APP2 chunks (large profiles
+// sometimes are) are not reassembled; only a single-chunk profile is read.
+func extractJPEGMetadata(path, ext string) (Metadata, error) {
+	if ext != "jpg" {
+		return Metadata{}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+
+	var soi [2]byte
+	if _, err := io.ReadFull(f, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return Metadata{}, errors.New("not a JPEG file")
+	}
+
+	var md Metadata
+	for {
+		marker, err := readMarker(f)
+		if err != nil {
+			break // EOF or malformed trailer: return whatever we found
+		}
+		if marker == 0xD8 || marker == 0xD9 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			continue // standalone markers, no length field
+		}
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(f, lenBuf[:]); err != nil {
+			break
+		}
+		segLen := int(binary.BigEndian.Uint16(lenBuf[:])) - 2
+		if segLen < 0 {
+			break
+		}
+		if marker == 0xDA {
+			break // start of scan: no more metadata segments
+		}
+		payload := make([]byte, segLen)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			break
+		}
+		switch {
+		case marker == 0xE1 && len(payload) > 6 && string(payload[:6]) == "Exif\x00\x00":
+			md.Exif = append([]byte(nil), payload[6:]...)
+		case marker == 0xE1 && bytes.HasPrefix(payload, xmpSignature):
+			md.Xmp = append([]byte(nil), payload[len(xmpSignature):]...)
+		case marker == 0xE2 && bytes.HasPrefix(payload, iccSignature) && len(payload) > len(iccSignature)+2:
+			// Chunk header is 1 sequence byte + 1 count byte; only accept
+			// count == 1 (a profile that fits in a single chunk) since we
+			// don't reassemble multi-chunk profiles (see doc comment above).
+			if payload[len(iccSignature)+1] == 1 {
+				md.ICC = append([]byte(nil), payload[len(iccSignature)+2:]...)
+			}
+		}
+	}
+	return md, nil
+}
+
+// writeTempBlob writes data to a new temp file in dir and returns its path
+// plus a cleanup func to remove it.
+func writeTempBlob(dir, pattern string, data []byte) (string, func(), error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", func() {}, err
+	}
+	path := f.Name()
+	cleanup := func() { os.Remove(path) }
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		cleanup()
+		return "", func() {}, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return path, cleanup, nil
+}
+
+// copyMetadataViaExiftool is the fallback path for encoder backends that
+// can't embed metadata themselves (ffmpeg, libavif): it shells out to
+// exiftool to copy EXIF/XMP/ICC straight from src onto dst in place. It's
+// best-effort - callers should not fail the conversion if this errors.
+func copyMetadataViaExiftool(srcPath, dstPath string) error {
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		return fmt.Errorf("exiftool: %w", err)
+	}
+	cmd := exec.Command("exiftool", "-TagsFromFile", srcPath, "-all:all", "-overwrite_original", dstPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("exiftool failed: %v; output: %s", err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// reportFileName is written to outDir once a run finishes, listing every
+// ConvertResult for downstream tooling (photo managers, dedup scripts, ...).
+const reportFileName = "report.json"
+
+// ReportEntry is the JSON shape of one ConvertResult in report.json.
+type ReportEntry struct {
+	SourcePath string     `json:"source_path"`
+	Dst        string     `json:"dst,omitempty"`
+	Digest     string     `json:"digest,omitempty"`
+	Encoder    string     `json:"encoder"`
+	DurationMS int64      `json:"duration_ms"`
+	OrigBytes  int64      `json:"orig_bytes"`
+	ConvBytes  int64      `json:"conv_bytes,omitempty"`
+	Dup        bool       `json:"dup,omitempty"`
+	Metadata   *MetaFlags `json:"metadata,omitempty"`
+	Err        string     `json:"error,omitempty"`
+}
+
+// MetaFlags records which metadata kinds were present in the source and
+// carried over to (or attempted onto) the output.
+type MetaFlags struct {
+	Exif bool `json:"exif"`
+	Xmp  bool `json:"xmp"`
+	ICC  bool `json:"icc"`
+}
+
+func reportEntryFrom(r ConvertResult, encoderName string) ReportEntry {
+	e := ReportEntry{
+		SourcePath: r.Src,
+		Dst:        r.Dst,
+		Digest:     r.Digest,
+		Encoder:    encoderName,
+		DurationMS: r.Duration.Milliseconds(),
+		OrigBytes:  r.OrigBytes,
+		ConvBytes:  r.ConvBytes,
+		Dup:        r.Dup,
+	}
+	if r.MetaExif || r.MetaXmp || r.MetaICC {
+		e.Metadata = &MetaFlags{Exif: r.MetaExif, Xmp: r.MetaXmp, ICC: r.MetaICC}
+	}
+	if r.Err != nil {
+		e.Err = r.Err.Error()
+	}
+	return e
+}
+
+// reportEntryFromManifest builds a report.json entry for a file that was
+// skipped this run because the manifest cache already had it converted.
+func reportEntryFromManifest(e ManifestEntry) ReportEntry {
+	return ReportEntry{
+		SourcePath: e.SourcePath,
+		Dst:        e.Dst,
+		Digest:     e.Digest,
+		Encoder:    e.Encoder,
+		OrigBytes:  e.Size,
+		ConvBytes:  e.ConvBytes,
+		Dup:        false,
+	}
+}
+
+func writeReport(path string, entries []ReportEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// SidecarInfo is the JSON shape of the optional per-file `<dst>.json`,
+// written next to each output when -sidecar is set. Unlike ReportEntry it
+// includes the actual preserved metadata blobs (base64-encoded via the
+// []byte JSON encoding), not just presence flags.
+type SidecarInfo struct {
+	SourcePath string        `json:"source_path"`
+	Digest     string        `json:"digest,omitempty"`
+	Encoder    string        `json:"encoder"`
+	Options    EncodeOptions `json:"options"`
+	Metadata   *Metadata     `json:"metadata,omitempty"`
+	DurationMS int64         `json:"duration_ms"`
+	OrigBytes  int64         `json:"orig_bytes"`
+	ConvBytes  int64         `json:"conv_bytes"`
+}
+
+func writeSidecar(dstPath string, info SidecarInfo) error {
+	if info.Metadata != nil && !info.Metadata.any() {
+		info.Metadata = nil
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sidecar: %w", err)
+	}
+	if err := os.WriteFile(dstPath+".json", data, 0644); err != nil {
+		return fmt.Errorf("write sidecar: %w", err)
+	}
+	return nil
+}